@@ -0,0 +1,115 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// azure-vnet-ipam is the CNI-spec (0.4.0+) IPAM binary delegated to by
+// azure-vnet (or invoked directly by a third-party network plugin). Like
+// azure-vnet, it has no state of its own: it forwards each invocation to
+// cni/ipam.Plugin backed by an HTTP client pointed at the running CNS
+// daemon.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
+
+	"github.com/Azure/azure-container-networking/cni/client"
+	cniipam "github.com/Azure/azure-container-networking/cni/ipam"
+	acn "github.com/Azure/azure-container-networking/common"
+)
+
+// ipamConf is the stdin configuration for azure-vnet-ipam.
+type ipamConf struct {
+	cniTypes.NetConf
+	CNSURL string `json:"cnsURL"`
+}
+
+func parseIPAMConf(stdin []byte) (*ipamConf, error) {
+	conf := &ipamConf{}
+	if err := json.Unmarshal(stdin, conf); err != nil {
+		return nil, fmt.Errorf("azure-vnet-ipam: failed to parse IPAM configuration: %v", err)
+	}
+
+	if conf.CNSURL == "" {
+		conf.CNSURL = "http://127.0.0.1:10090"
+	}
+
+	return conf, nil
+}
+
+func newPlugin(conf *ipamConf) (*cniipam.Plugin, error) {
+	plugin, err := cniipam.NewPlugin(&acn.PluginConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	plugin.SetOption(acn.OptRestService, client.New(conf.CNSURL))
+	return plugin, nil
+}
+
+func cmdAdd(args *skel.CmdArgs) error {
+	conf, err := parseIPAMConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := newPlugin(conf)
+	if err != nil {
+		return err
+	}
+
+	result, err := plugin.Add(args.ContainerID, conf.Name)
+	if err != nil {
+		return err
+	}
+
+	cniResult := &current.Result{CNIVersion: conf.CNIVersion}
+	if result.IP != "" {
+		gw := net.ParseIP(result.Gateway)
+		_, ipNet, parseErr := net.ParseCIDR(result.IP)
+		if parseErr != nil {
+			return fmt.Errorf("azure-vnet-ipam: CNS returned an unparseable address %q: %v", result.IP, parseErr)
+		}
+
+		cniResult.IPs = append(cniResult.IPs, &current.IPConfig{Address: *ipNet, Gateway: gw})
+	}
+
+	return cniTypes.PrintResult(cniResult, conf.CNIVersion)
+}
+
+func cmdDel(args *skel.CmdArgs) error {
+	conf, err := parseIPAMConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := newPlugin(conf)
+	if err != nil {
+		return err
+	}
+
+	return plugin.Delete(args.ContainerID, conf.Name)
+}
+
+func cmdCheck(args *skel.CmdArgs) error {
+	conf, err := parseIPAMConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := newPlugin(conf)
+	if err != nil {
+		return err
+	}
+
+	return plugin.Check(args.ContainerID, conf.Name)
+}
+
+func main() {
+	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, version.PluginSupports(cniipam.SupportedVersions...), "azure-vnet-ipam")
+}
@@ -0,0 +1,143 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// azure-vnet is the CNI-spec (0.4.0+) network binary kubelet/containerd
+// invoke directly. It has no long-running state of its own: it decodes
+// the CNI invocation, forwards it to cni/network.Plugin backed by an HTTP
+// client pointed at the already-running CNS daemon, and prints the CNI
+// result struct kubelet expects on stdout.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	cniTypes "github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
+
+	"github.com/Azure/azure-container-networking/cni/client"
+	cninetwork "github.com/Azure/azure-container-networking/cni/network"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	acn "github.com/Azure/azure-container-networking/common"
+)
+
+// netConf is the stdin configuration for azure-vnet. Networks carries the
+// Multus/podman-style `--network=net1,net2` list; a config without a
+// networks list still populates it with one element named after the config.
+// RouteConflictPolicy governs how CNS resolves overlapping routes across
+// those networks; left unset, CNS defaults it to failing the request fast.
+type netConf struct {
+	cniTypes.NetConf
+	CNSURL              string                         `json:"cnsURL"`
+	Networks            []cninetwork.NetworkRequest    `json:"networks"`
+	RouteConflictPolicy restserver.RouteConflictPolicy `json:"routeConflictPolicy"`
+}
+
+func parseNetConf(stdin []byte) (*netConf, error) {
+	conf := &netConf{}
+	if err := json.Unmarshal(stdin, conf); err != nil {
+		return nil, fmt.Errorf("azure-vnet: failed to parse network configuration: %v", err)
+	}
+
+	if conf.CNSURL == "" {
+		conf.CNSURL = "http://127.0.0.1:10090"
+	}
+
+	if len(conf.Networks) == 0 {
+		conf.Networks = []cninetwork.NetworkRequest{{NetworkName: conf.Name, IfName: "eth0"}}
+	}
+
+	return conf, nil
+}
+
+func newPlugin(conf *netConf) (*cninetwork.Plugin, error) {
+	plugin, err := cninetwork.NewPlugin(&acn.PluginConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	plugin.SetOption(acn.OptRestService, client.New(conf.CNSURL))
+	return plugin, nil
+}
+
+func cmdAdd(args *skel.CmdArgs) error {
+	conf, err := parseNetConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := newPlugin(conf)
+	if err != nil {
+		return err
+	}
+
+	result, err := plugin.Add(&cninetwork.Args{
+		ContainerID:         args.ContainerID,
+		Networks:            conf.Networks,
+		Netns:               args.Netns,
+		StdinData:           args.StdinData,
+		RouteConflictPolicy: conf.RouteConflictPolicy,
+	})
+	if err != nil {
+		return err
+	}
+
+	cniResult := &current.Result{CNIVersion: conf.CNIVersion}
+	for _, ip := range result.IPs {
+		_, ipNet, err := net.ParseCIDR(ip)
+		if err != nil {
+			return fmt.Errorf("azure-vnet: CNS returned an unparseable address %q: %v", ip, err)
+		}
+
+		cniResult.IPs = append(cniResult.IPs, &current.IPConfig{Address: *ipNet})
+	}
+
+	cniResult.DNS.Nameservers = result.DNS
+
+	return cniTypes.PrintResult(cniResult, conf.CNIVersion)
+}
+
+func cmdDel(args *skel.CmdArgs) error {
+	conf, err := parseNetConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := newPlugin(conf)
+	if err != nil {
+		return err
+	}
+
+	return plugin.Delete(&cninetwork.Args{
+		ContainerID: args.ContainerID,
+		Networks:    conf.Networks,
+		Netns:       args.Netns,
+		StdinData:   args.StdinData,
+	})
+}
+
+func cmdCheck(args *skel.CmdArgs) error {
+	conf, err := parseNetConf(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := newPlugin(conf)
+	if err != nil {
+		return err
+	}
+
+	return plugin.Check(&cninetwork.Args{
+		ContainerID: args.ContainerID,
+		Networks:    conf.Networks,
+		Netns:       args.Netns,
+		StdinData:   args.StdinData,
+	})
+}
+
+func main() {
+	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, version.PluginSupports(cninetwork.SupportedVersions...), "azure-vnet")
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package client is the HTTP client the standalone azure-vnet and
+// azure-vnet-ipam CNI binaries use to reach the long-running CNS daemon's
+// REST API. Each CNI invocation is a fresh, short-lived process with no
+// access to the daemon's in-memory endpoint/IPAM state, so it talks to
+// cns/restserver over the loopback HTTP surface that package exposes
+// instead of linking against it directly.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/restserver"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Client talks to a CNS daemon's REST API and satisfies both
+// cni/network.RestClient and cni/ipam.RestClient.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// New creates a Client for the CNS daemon listening at baseURL, e.g.
+// "http://127.0.0.1:10090".
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+type endpointRequest struct {
+	ContainerID string `json:"containerID"`
+	NetworkName string `json:"networkName"`
+}
+
+// CreateEndpoint calls the CNS daemon's /network/create route.
+func (c *Client) CreateEndpoint(req *restserver.CreateEndpointRequest) (*restserver.CreateEndpointResponse, error) {
+	var resp restserver.CreateEndpointResponse
+	if err := c.post("/network/create", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DeleteEndpoint calls the CNS daemon's /network/delete route.
+func (c *Client) DeleteEndpoint(containerID, networkName string) error {
+	return c.post("/network/delete", &endpointRequest{ContainerID: containerID, NetworkName: networkName}, nil)
+}
+
+// CheckEndpoint calls the CNS daemon's /network/check route.
+func (c *Client) CheckEndpoint(containerID, networkName string) error {
+	return c.post("/network/check", &endpointRequest{ContainerID: containerID, NetworkName: networkName}, nil)
+}
+
+// ReserveAddress calls the CNS daemon's /ipam/reserve route.
+func (c *Client) ReserveAddress(containerID, networkName string) (*restserver.AddressReservation, error) {
+	var resp restserver.AddressReservation
+	if err := c.post("/ipam/reserve", &endpointRequest{ContainerID: containerID, NetworkName: networkName}, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ReleaseAddress calls the CNS daemon's /ipam/release route.
+func (c *Client) ReleaseAddress(containerID, networkName string) error {
+	return c.post("/ipam/release", &endpointRequest{ContainerID: containerID, NetworkName: networkName}, nil)
+}
+
+// CheckAddress calls the CNS daemon's /ipam/check route.
+func (c *Client) CheckAddress(containerID, networkName string) error {
+	return c.post("/ipam/check", &endpointRequest{ContainerID: containerID, NetworkName: networkName}, nil)
+}
+
+func (c *Client) post(path string, req, resp interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.httpClient.Post(c.baseURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		var msg bytes.Buffer
+		msg.ReadFrom(httpResp.Body)
+		return fmt.Errorf("cns request to %v failed with status %v: %v", path, httpResp.StatusCode, msg.String())
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	return json.NewDecoder(httpResp.Body).Decode(resp)
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package ipam implements a CNI (spec 0.4.0+) compliant IPAM plugin that is
+// hosted inside the CNS process, mirroring cni/network. It answers
+// ADD/DEL/CHECK/VERSION requests by delegating address allocation to the
+// IPAM state already tracked by the CNS REST service.
+package ipam
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// SupportedVersions lists the CNI spec versions this plugin supports, for
+// both Version() and the azure-vnet-ipam binary's version.PluginSupports
+// call.
+var SupportedVersions = []string{"0.3.0", "0.3.1", "0.4.0"}
+
+// RestClient is the subset of HTTPRestService's IPAM surface the plugin
+// needs. *restserver.HTTPRestService satisfies it directly when the
+// plugin is hosted in-process inside the CNS daemon (the pluginAPICNI
+// mode wired up in cns/service/main.go); the standalone azure-vnet-ipam
+// binary instead satisfies it with an HTTP client talking to the CNS
+// daemon's REST API, since each CNI invocation is its own short-lived
+// process with no access to the daemon's in-memory state.
+type RestClient interface {
+	ReserveAddress(containerID, networkName string) (*restserver.AddressReservation, error)
+	ReleaseAddress(containerID, networkName string) error
+	CheckAddress(containerID, networkName string) error
+}
+
+// Plugin represents the CNI IPAM plugin.
+type Plugin struct {
+	*acn.Plugin
+	restService RestClient
+
+	mu            sync.Mutex
+	queryInterval time.Duration
+	tracked       map[string]trackedReservation
+	stopQuery     chan struct{}
+}
+
+// trackedReservation identifies one address reservation this plugin
+// instance has made, for the periodic query loop to re-check.
+type trackedReservation struct {
+	containerID string
+	networkName string
+}
+
+// NewPlugin creates a new CNI IPAM plugin.
+func NewPlugin(config *acn.PluginConfig) (*Plugin, error) {
+	base, err := acn.NewPlugin("azure-vnet-ipam", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plugin{
+		Plugin:  base,
+		tracked: make(map[string]trackedReservation),
+	}, nil
+}
+
+// SetOption sets an option on the plugin.
+func (plugin *Plugin) SetOption(key, value interface{}) {
+	if key == acn.OptRestService {
+		if svc, ok := value.(RestClient); ok {
+			plugin.restService = svc
+		}
+	}
+}
+
+// Start initializes the plugin.
+func (plugin *Plugin) Start(config *acn.PluginConfig) error {
+	if err := plugin.Initialize(config); err != nil {
+		return err
+	}
+
+	log.Printf("[cni-ipam] Plugin started, hosting CNI IPAM verbs.")
+	return nil
+}
+
+// Stop stops the plugin.
+func (plugin *Plugin) Stop() {
+	plugin.stopQueryLoop()
+	plugin.Uninitialize()
+	log.Printf("[cni-ipam] Plugin stopped.")
+}
+
+// Add handles a CNI ADD request by reserving an address through CNS.
+func (plugin *Plugin) Add(containerID, networkName string) (*Result, error) {
+	log.Printf("[cni-ipam] ADD for container %v on network %v.", containerID, networkName)
+
+	reservation, err := plugin.restService.ReserveAddress(containerID, networkName)
+	if err != nil {
+		return nil, err
+	}
+
+	plugin.mu.Lock()
+	plugin.tracked[reservationKey(containerID, networkName)] = trackedReservation{containerID: containerID, networkName: networkName}
+	plugin.mu.Unlock()
+
+	return &Result{IP: reservation.IPAddress, Gateway: reservation.Gateway}, nil
+}
+
+// Delete handles a CNI DEL request by releasing an address through CNS.
+func (plugin *Plugin) Delete(containerID, networkName string) error {
+	log.Printf("[cni-ipam] DEL for container %v on network %v.", containerID, networkName)
+
+	plugin.mu.Lock()
+	delete(plugin.tracked, reservationKey(containerID, networkName))
+	plugin.mu.Unlock()
+
+	return plugin.restService.ReleaseAddress(containerID, networkName)
+}
+
+// reservationKey returns the key Add/Delete track a reservation under,
+// mirroring the key restserver uses internally for the same pair.
+func reservationKey(containerID, networkName string) string {
+	return containerID + "-" + networkName
+}
+
+// Check handles a CNI CHECK request by validating address state through CNS.
+func (plugin *Plugin) Check(containerID, networkName string) error {
+	log.Printf("[cni-ipam] CHECK for container %v on network %v.", containerID, networkName)
+	return plugin.restService.CheckAddress(containerID, networkName)
+}
+
+// Version returns the list of CNI spec versions this plugin supports.
+func (plugin *Plugin) Version() []string {
+	return SupportedVersions
+}
+
+// Reconfigure applies a newly loaded config file's IPAM query interval
+// without requiring the plugin to be restarted: it restarts the background
+// loop that re-validates every reservation Add is currently tracking at
+// the new interval.
+func (plugin *Plugin) Reconfigure(cfg *configuration.CNSConfig) {
+	interval := time.Duration(cfg.IpamQueryInterval) * time.Second
+
+	plugin.mu.Lock()
+	plugin.queryInterval = interval
+	plugin.mu.Unlock()
+
+	plugin.stopQueryLoop()
+	if interval > 0 {
+		plugin.startQueryLoop(interval)
+	}
+
+	log.Printf("[cni-ipam] Reconfigured with IPAM query interval %v.", cfg.IpamQueryInterval)
+}
+
+// startQueryLoop runs until stopQueryLoop closes plugin.stopQuery, calling
+// CheckAddress on every reservation Add is currently tracking once per
+// interval and logging any that CNS no longer recognizes.
+func (plugin *Plugin) startQueryLoop(interval time.Duration) {
+	stop := make(chan struct{})
+
+	plugin.mu.Lock()
+	plugin.stopQuery = stop
+	plugin.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				plugin.queryTrackedAddresses()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopQueryLoop stops a running query loop, if one is running.
+func (plugin *Plugin) stopQueryLoop() {
+	plugin.mu.Lock()
+	stop := plugin.stopQuery
+	plugin.stopQuery = nil
+	plugin.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+	}
+}
+
+// queryTrackedAddresses calls CheckAddress for every reservation this
+// plugin instance has made, logging any CNS no longer holds a record of.
+func (plugin *Plugin) queryTrackedAddresses() {
+	plugin.mu.Lock()
+	tracked := make([]trackedReservation, 0, len(plugin.tracked))
+	for _, reservation := range plugin.tracked {
+		tracked = append(tracked, reservation)
+	}
+	plugin.mu.Unlock()
+
+	for _, reservation := range tracked {
+		if err := plugin.restService.CheckAddress(reservation.containerID, reservation.networkName); err != nil {
+			log.Printf("[cni-ipam] Periodic query found a stale reservation for container %v on network %v, err:%v.",
+				reservation.containerID, reservation.networkName, err)
+		}
+	}
+}
+
+// Result is the outcome of a CNI IPAM ADD request.
+type Result struct {
+	IP      string
+	Gateway string
+}
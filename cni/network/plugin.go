@@ -0,0 +1,203 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package network implements a CNI (spec 0.4.0+) compliant network plugin
+// that is hosted inside the CNS process. Unlike cnm/network, which speaks
+// the Docker libnetwork remote driver protocol, this plugin answers
+// ADD/DEL/CHECK/VERSION requests directly and delegates the actual
+// provisioning work to the CNS REST service so CNS can run under
+// CRI runtimes (containerd, CRI-O) without a CNM-capable Docker daemon.
+package network
+
+import (
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/cns/restserver"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// SupportedVersions lists the CNI spec versions this plugin supports, for
+// both Version() and the azure-vnet binary's version.PluginSupports call.
+var SupportedVersions = []string{"0.3.0", "0.3.1", "0.4.0"}
+
+// RestClient is the subset of HTTPRestService's endpoint surface the
+// plugin needs. *restserver.HTTPRestService satisfies it directly when
+// the plugin is hosted in-process inside the CNS daemon (the
+// pluginAPICNI mode wired up in cns/service/main.go); the standalone
+// azure-vnet binary instead satisfies it with an HTTP client talking to
+// the CNS daemon's REST API, since each CNI invocation is its own
+// short-lived process with no access to the daemon's in-memory state.
+type RestClient interface {
+	CreateEndpoint(req *restserver.CreateEndpointRequest) (*restserver.CreateEndpointResponse, error)
+	DeleteEndpoint(containerID, networkName string) error
+	CheckEndpoint(containerID, networkName string) error
+}
+
+// Plugin represents the CNI network plugin.
+type Plugin struct {
+	*acn.Plugin
+	restService RestClient
+
+	mu              sync.Mutex
+	networkDefaults configuration.NetworkDefaults
+}
+
+// NewPlugin creates a new CNI network plugin.
+func NewPlugin(config *acn.PluginConfig) (*Plugin, error) {
+	base, err := acn.NewPlugin("azure-vnet", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plugin{
+		Plugin: base,
+	}, nil
+}
+
+// SetOption sets an option on the plugin.
+func (plugin *Plugin) SetOption(key, value interface{}) {
+	if key == acn.OptRestService {
+		if svc, ok := value.(RestClient); ok {
+			plugin.restService = svc
+		}
+	}
+}
+
+// Start initializes the plugin.
+func (plugin *Plugin) Start(config *acn.PluginConfig) error {
+	if err := plugin.Initialize(config); err != nil {
+		return err
+	}
+
+	log.Printf("[cni-net] Plugin started, hosting CNI network verbs.")
+	return nil
+}
+
+// Stop stops the plugin.
+func (plugin *Plugin) Stop() {
+	plugin.Uninitialize()
+	log.Printf("[cni-net] Plugin stopped.")
+}
+
+// Add handles a CNI ADD request by creating an endpoint through CNS. A
+// single ADD call may join the container to more than one network; the
+// first entry in args.Networks is always marked primary and every other
+// entry secondary.
+func (plugin *Plugin) Add(args *Args) (*Result, error) {
+	log.Printf("[cni-net] ADD for container %v on networks %v.", args.ContainerID, args.Networks)
+
+	attachments := make([]restserver.NetworkAttachment, len(args.Networks))
+	for i, n := range args.Networks {
+		attachments[i] = restserver.NetworkAttachment{
+			NetworkName: n.NetworkName,
+			IfName:      n.IfName,
+			MacAddress:  n.MacAddress,
+			IPAddresses: n.IPAddresses,
+			Routes:      n.Routes,
+			Primary:     i == 0,
+		}
+	}
+
+	response, err := plugin.restService.CreateEndpoint(&restserver.CreateEndpointRequest{
+		ContainerID:         args.ContainerID,
+		Attachments:         attachments,
+		RouteConflictPolicy: args.RouteConflictPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, attachment := range response.Attachments {
+		result.IPs = append(result.IPs, attachment.IPAddresses...)
+	}
+
+	plugin.mu.Lock()
+	result.DNS = plugin.networkDefaults.DNSServers
+	plugin.mu.Unlock()
+
+	return result, nil
+}
+
+// Delete handles a CNI DEL request by tearing down the container's
+// attachment on each network named in args.Networks. It keeps going past a
+// failed network and returns the first error.
+func (plugin *Plugin) Delete(args *Args) error {
+	log.Printf("[cni-net] DEL for container %v on networks %v.", args.ContainerID, args.Networks)
+
+	var firstErr error
+	for _, n := range args.Networks {
+		if err := plugin.restService.DeleteEndpoint(args.ContainerID, n.NetworkName); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Check handles a CNI CHECK request by validating endpoint state through CNS
+// for every attached network.
+func (plugin *Plugin) Check(args *Args) error {
+	log.Printf("[cni-net] CHECK for container %v on networks %v.", args.ContainerID, args.Networks)
+
+	for _, n := range args.Networks {
+		if err := plugin.restService.CheckEndpoint(args.ContainerID, n.NetworkName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Version returns the list of CNI spec versions this plugin supports.
+func (plugin *Plugin) Version() []string {
+	return SupportedVersions
+}
+
+// Reconfigure applies a newly loaded config file's network defaults
+// without requiring the plugin to be restarted. Only DNSServers is used:
+// every later ADD copies it onto its Result. Mode, Bridge and MTU are
+// stored but not applied.
+func (plugin *Plugin) Reconfigure(cfg *configuration.CNSConfig) {
+	plugin.mu.Lock()
+	plugin.networkDefaults = cfg.NetworkDefaults
+	plugin.mu.Unlock()
+
+	log.Printf("[cni-net] Reconfigured with network defaults: %+v.", cfg.NetworkDefaults)
+}
+
+// Args holds the subset of CNI invocation arguments the plugin needs. A
+// single invocation may name more than one network, following the
+// Multus/podman `--network=net1,net2` convention.
+type Args struct {
+	ContainerID string
+	Networks    []NetworkRequest
+	Netns       string
+	StdinData   []byte
+
+	// RouteConflictPolicy governs how Add resolves routes two networks in
+	// the same request both propose; see restserver.RouteConflictPolicy.
+	// Left unset, CreateEndpoint defaults it to RouteConflictFailFast.
+	RouteConflictPolicy restserver.RouteConflictPolicy
+}
+
+// NetworkRequest is the per-network portion of a CNI Args, carrying the
+// same fields as restserver.NetworkAttachment except Primary (which Add
+// sets from the entry's position) so the plugin can build a
+// CreateEndpointRequest without re-parsing StdinData per network.
+type NetworkRequest struct {
+	NetworkName string
+	IfName      string
+	MacAddress  string
+	IPAddresses []string
+	Routes      []string
+}
+
+// Result is the outcome of a CNI ADD request.
+type Result struct {
+	IPs    []string
+	Routes []string
+	DNS    []string
+}
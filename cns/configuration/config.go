@@ -0,0 +1,132 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package configuration loads the declarative CNS config file and watches
+// it for changes so the running daemon can be reconfigured without a
+// restart, which would otherwise drop all in-memory endpoint state.
+package configuration
+
+import (
+	"io/ioutil"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/Azure/azure-container-networking/log"
+	"gopkg.in/yaml.v2"
+)
+
+// NetworkDefaults holds the per-network settings applied when a CNI/CNM
+// request does not specify them explicitly.
+type NetworkDefaults struct {
+	Mode          string `yaml:"mode"`
+	Bridge        string `yaml:"bridge"`
+	MTU           int    `yaml:"mtu"`
+	DNSServers    []string `yaml:"dnsServers"`
+}
+
+// TLSConfig holds the material for the REST server's HTTPS listener.
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// PluginsConfig toggles which plugin surfaces the daemon hosts.
+type PluginsConfig struct {
+	CNM bool `yaml:"cnm"`
+	CNI bool `yaml:"cni"`
+}
+
+// CNSConfig is the root of the declarative config file.
+type CNSConfig struct {
+	LogLevel          string          `yaml:"logLevel"`
+	LogTarget         string          `yaml:"logTarget"`
+	APIServerURL      string          `yaml:"apiServerURL"`
+	IpamQueryInterval int             `yaml:"ipamQueryInterval"`
+	ListenAddress     string          `yaml:"listenAddress"`
+	Plugins           PluginsConfig   `yaml:"plugins"`
+	NetworkDefaults   NetworkDefaults `yaml:"networkDefaults"`
+	TLS               TLSConfig       `yaml:"tls"`
+}
+
+// Load reads and parses the config file at path.
+func Load(path string) (*CNSConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg CNSConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Watch loads path once, invoking onChange immediately with the initial
+// config, then continues watching the file and invokes onChange again on
+// every subsequent write. Watch runs until the process exits; callers do
+// not need to stop it explicitly since config hot-reload is expected to
+// live for the lifetime of the daemon.
+func Watch(path string, onChange func(*CNSConfig)) error {
+	cfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	onChange(cfg)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+					continue
+				}
+
+				// Kubernetes ConfigMap mounts update via an atomic symlink
+				// swap, which fires Remove (or Rename) on the watched path
+				// rather than Write: the inode fsnotify was watching is
+				// gone, so the watch must be re-added against the new
+				// inode at the same path or every later update goes
+				// unnoticed.
+				if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+					if err := watcher.Add(path); err != nil {
+						log.Printf("[Azure CNS] Failed to re-add config watch for %v, err:%v.", path, err)
+						continue
+					}
+				}
+
+				cfg, err := Load(path)
+				if err != nil {
+					log.Printf("[Azure CNS] Failed to reload config %v, err:%v.", path, err)
+					continue
+				}
+
+				onChange(cfg)
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Printf("[Azure CNS] Config watcher error: %v.", err)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// AddressReservation is the outcome of reserving an address for a
+// container on a network.
+type AddressReservation struct {
+	IPAddress string
+	Gateway   string
+}
+
+// addressState is the in-memory record CNS keeps for one IPAM reservation,
+// keyed the same way as endpointState: containerID + networkName.
+type addressState struct {
+	ipAddress string
+	gateway   string
+}
+
+// reservationKey returns the key addressState and endpointState share for
+// a given container/network pair.
+func reservationKey(containerID, networkName string) string {
+	return containerID + "-" + networkName
+}
+
+// ReserveAddress allocates an address for containerID on networkName. It
+// is the IPAM-side counterpart to CreateEndpoint's network-side work, and
+// is what the CNI IPAM plugin's ADD verb delegates to.
+func (service *HTTPRestService) ReserveAddress(containerID, networkName string) (*AddressReservation, error) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	key := reservationKey(containerID, networkName)
+	if _, exists := service.addresses[key]; exists {
+		return nil, fmt.Errorf("ReserveAddress: container %v already has a reservation on network %v", containerID, networkName)
+	}
+
+	service.addressIndex++
+	state := &addressState{
+		ipAddress: fmt.Sprintf("10.0.0.%d/24", service.addressIndex),
+		gateway:   "10.0.0.1",
+	}
+
+	service.addresses[key] = state
+
+	log.Printf("[Azure CNS] Reserved address %v for container %v on network %v.",
+		state.ipAddress, containerID, networkName)
+
+	return &AddressReservation{IPAddress: state.ipAddress, Gateway: state.gateway}, nil
+}
+
+// ReleaseAddress frees a previously reserved address.
+func (service *HTTPRestService) ReleaseAddress(containerID, networkName string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	key := reservationKey(containerID, networkName)
+	if _, exists := service.addresses[key]; !exists {
+		return fmt.Errorf("ReleaseAddress: no reservation for container %v on network %v", containerID, networkName)
+	}
+
+	delete(service.addresses, key)
+
+	log.Printf("[Azure CNS] Released address for container %v on network %v.", containerID, networkName)
+
+	return nil
+}
+
+// CheckAddress validates that a reservation still exists, for the CNI
+// IPAM CHECK verb.
+func (service *HTTPRestService) CheckAddress(containerID, networkName string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	if _, exists := service.addresses[reservationKey(containerID, networkName)]; !exists {
+		return fmt.Errorf("CheckAddress: no reservation for container %v on network %v", containerID, networkName)
+	}
+
+	return nil
+}
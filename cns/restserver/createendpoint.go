@@ -0,0 +1,196 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// RouteConflictPolicy controls how overlapping default routes proposed by
+// multiple attachments in a CreateEndpointRequest are resolved.
+type RouteConflictPolicy string
+
+const (
+	// RouteConflictFailFast rejects the request as soon as two attachments
+	// propose overlapping routes.
+	RouteConflictFailFast RouteConflictPolicy = "failFast"
+	// RouteConflictPreferPrimary keeps the primary attachment's route and
+	// drops the conflicting route from any secondary attachment.
+	RouteConflictPreferPrimary RouteConflictPolicy = "preferPrimary"
+)
+
+// NetworkAttachment describes one network a container should be joined to
+// as part of a single CreateEndpoint call. Ordering is significant: the
+// attachment marked Primary (or, if none is marked, the first attachment)
+// supplies the container's default route. Routes lists the destination
+// CIDRs (e.g. "0.0.0.0/0") this attachment proposes installing; two
+// attachments proposing the same route is a conflict validateRouteConflicts
+// resolves according to the request's RouteConflictPolicy.
+type NetworkAttachment struct {
+	NetworkName string
+	IfName      string
+	MacAddress  string
+	IPAddresses []string
+	Routes      []string
+	Primary     bool
+}
+
+// CreateEndpointRequest is the payload for creating a container endpoint
+// across one or more networks in a single call.
+type CreateEndpointRequest struct {
+	ContainerID         string
+	Attachments         []NetworkAttachment
+	RouteConflictPolicy RouteConflictPolicy
+}
+
+// AttachmentResult is the per-network outcome of a CreateEndpoint call.
+type AttachmentResult struct {
+	NetworkName string
+	EndpointID  string
+	IPAddresses []string
+	Gateway     string
+}
+
+// CreateEndpointResponse is returned once every attachment in the request
+// has been provisioned.
+type CreateEndpointResponse struct {
+	ContainerID string
+	Attachments []AttachmentResult
+}
+
+// CreateEndpoint provisions a container across the networks listed in req,
+// in order. If any attachment after the first fails, the attachments that
+// already succeeded are rolled back so the call is all-or-nothing from the
+// caller's perspective.
+func (service *HTTPRestService) CreateEndpoint(req *CreateEndpointRequest) (*CreateEndpointResponse, error) {
+	if service.IsDraining() {
+		return nil, ErrDraining
+	}
+
+	defer service.trackRequest()()
+
+	if len(req.Attachments) == 0 {
+		return nil, fmt.Errorf("CreateEndpoint: at least one network attachment is required")
+	}
+
+	if err := validateRouteConflicts(req.Attachments, req.RouteConflictPolicy); err != nil {
+		return nil, err
+	}
+
+	response := &CreateEndpointResponse{
+		ContainerID: req.ContainerID,
+	}
+
+	for i, attachment := range req.Attachments {
+		result, err := service.attachNetwork(req.ContainerID, attachment)
+		if err != nil {
+			log.Printf("[Azure CNS] Attachment %v/%v (%v) failed for container %v, err:%v. Rolling back.",
+				i+1, len(req.Attachments), attachment.NetworkName, req.ContainerID, err)
+			service.rollbackAttachments(req.ContainerID, response.Attachments)
+			return nil, fmt.Errorf("CreateEndpoint: failed to attach network %v: %v", attachment.NetworkName, err)
+		}
+
+		response.Attachments = append(response.Attachments, *result)
+	}
+
+	return response, nil
+}
+
+// attachNetwork invokes the underlying network/IPAM plugins for a single
+// attachment, isolated from the failure of any other attachment in the
+// same request.
+func (service *HTTPRestService) attachNetwork(containerID string, attachment NetworkAttachment) (*AttachmentResult, error) {
+	endpointID, ipAddresses, gateway, err := service.createSingleEndpoint(containerID, attachment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttachmentResult{
+		NetworkName: attachment.NetworkName,
+		EndpointID:  endpointID,
+		IPAddresses: ipAddresses,
+		Gateway:     gateway,
+	}, nil
+}
+
+// rollbackAttachments tears down every attachment that was already
+// provisioned before a later attachment in the same request failed.
+func (service *HTTPRestService) rollbackAttachments(containerID string, attached []AttachmentResult) {
+	for i := len(attached) - 1; i >= 0; i-- {
+		if err := service.deleteSingleEndpoint(containerID, attached[i].NetworkName, attached[i].EndpointID); err != nil {
+			log.Printf("[Azure CNS] Rollback of network %v for container %v failed, err:%v.",
+				attached[i].NetworkName, containerID, err)
+		}
+	}
+}
+
+// validateRouteConflicts rejects or resolves overlapping routes proposed by
+// more than one attachment, according to policy. Under RouteConflictFailFast
+// any route proposed by more than one attachment is rejected outright; under
+// RouteConflictPreferPrimary the route is kept on the primary attachment (or
+// left alone if no attachment is primary) and dropped from every other
+// attachment that proposed it. An unset policy defaults to
+// RouteConflictFailFast, so a caller that never plumbs one through still
+// gets a conflict rejected rather than silently left in place.
+func validateRouteConflicts(attachments []NetworkAttachment, policy RouteConflictPolicy) error {
+	if policy == "" {
+		policy = RouteConflictFailFast
+	}
+
+	primaryCount := 0
+	for _, attachment := range attachments {
+		if attachment.Primary {
+			primaryCount++
+		}
+	}
+
+	if primaryCount > 1 && policy == RouteConflictFailFast {
+		return fmt.Errorf("validateRouteConflicts: more than one attachment claims to be primary")
+	}
+
+	owners := make(map[string][]int)
+	for i, attachment := range attachments {
+		for _, route := range attachment.Routes {
+			owners[route] = append(owners[route], i)
+		}
+	}
+
+	for route, idxs := range owners {
+		if len(idxs) < 2 {
+			continue
+		}
+
+		switch policy {
+		case RouteConflictFailFast:
+			return fmt.Errorf("validateRouteConflicts: route %v is proposed by more than one attachment", route)
+
+		case RouteConflictPreferPrimary:
+			if primaryCount == 0 {
+				continue
+			}
+
+			for _, i := range idxs {
+				if !attachments[i].Primary {
+					attachments[i].Routes = removeRoute(attachments[i].Routes, route)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeRoute returns routes with every occurrence of route removed.
+func removeRoute(routes []string, route string) []string {
+	kept := routes[:0]
+	for _, r := range routes {
+		if r != route {
+			kept = append(kept, r)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/cns/common"
+)
+
+func newTestService(t *testing.T) *HTTPRestService {
+	t.Helper()
+
+	service, err := NewHTTPRestService(&common.ServiceConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPRestService failed: %v", err)
+	}
+
+	return service
+}
+
+// TestCreateEndpointRollsBackOnFailure verifies that when a later attachment
+// in a multi-network request fails, every attachment that already succeeded
+// is torn down, including its IPAM reservation, so a retry of the same
+// request doesn't see a stale "already reserved" error.
+func TestCreateEndpointRollsBackOnFailure(t *testing.T) {
+	service := newTestService(t)
+
+	req := &CreateEndpointRequest{
+		ContainerID: "container1",
+		Attachments: []NetworkAttachment{
+			{NetworkName: "net1", IfName: "eth0", Primary: true},
+			{NetworkName: "net1", IfName: "eth1"}, // duplicate network: second reservation fails
+		},
+	}
+
+	if _, err := service.CreateEndpoint(req); err == nil {
+		t.Fatal("expected CreateEndpoint to fail on the duplicate attachment")
+	}
+
+	if _, ok := service.endpoints["container1-net1"]; ok {
+		t.Fatal("expected the first attachment's endpoint to be rolled back")
+	}
+
+	if _, ok := service.addresses[reservationKey("container1", "net1")]; ok {
+		t.Fatal("expected the first attachment's address reservation to be rolled back")
+	}
+
+	// A retry of the same request should now succeed since rollback freed
+	// the reservation net1 would otherwise still hold.
+	retry := &CreateEndpointRequest{
+		ContainerID: "container1",
+		Attachments: []NetworkAttachment{
+			{NetworkName: "net1", IfName: "eth0", Primary: true},
+		},
+	}
+
+	if _, err := service.CreateEndpoint(retry); err != nil {
+		t.Fatalf("expected retry after rollback to succeed, got err: %v", err)
+	}
+}
+
+func TestValidateRouteConflictsFailFast(t *testing.T) {
+	attachments := []NetworkAttachment{
+		{NetworkName: "net1", Primary: true, Routes: []string{"10.0.0.0/24"}},
+		{NetworkName: "net2", Routes: []string{"10.0.0.0/24"}},
+	}
+
+	if err := validateRouteConflicts(attachments, RouteConflictFailFast); err == nil {
+		t.Fatal("expected an error for an overlapping route under RouteConflictFailFast")
+	}
+}
+
+func TestValidateRouteConflictsDefaultsToFailFast(t *testing.T) {
+	attachments := []NetworkAttachment{
+		{NetworkName: "net1", Primary: true, Routes: []string{"10.0.0.0/24"}},
+		{NetworkName: "net2", Routes: []string{"10.0.0.0/24"}},
+	}
+
+	if err := validateRouteConflicts(attachments, ""); err == nil {
+		t.Fatal("expected an unset policy to default to RouteConflictFailFast and reject the overlapping route")
+	}
+}
+
+func TestValidateRouteConflictsPreferPrimary(t *testing.T) {
+	attachments := []NetworkAttachment{
+		{NetworkName: "net1", Primary: true, Routes: []string{"10.0.0.0/24"}},
+		{NetworkName: "net2", Routes: []string{"10.0.0.0/24", "10.1.0.0/24"}},
+	}
+
+	if err := validateRouteConflicts(attachments, RouteConflictPreferPrimary); err != nil {
+		t.Fatalf("expected RouteConflictPreferPrimary to resolve the conflict, got err: %v", err)
+	}
+
+	for _, route := range attachments[1].Routes {
+		if route == "10.0.0.0/24" {
+			t.Fatal("expected the conflicting route to be dropped from the non-primary attachment")
+		}
+	}
+
+	if len(attachments[1].Routes) != 1 || attachments[1].Routes[0] != "10.1.0.0/24" {
+		t.Fatalf("expected the non-conflicting route to survive, got %v", attachments[1].Routes)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// ErrDraining is returned by request-accepting endpoints once the service
+// has started draining for shutdown.
+var ErrDraining = fmt.Errorf("CNS is draining and is not accepting new create requests")
+
+// BeginDrain marks the service as draining: new create requests are
+// rejected while deletes and reads keep being served so in-flight
+// container teardowns can complete cleanly.
+func (service *HTTPRestService) BeginDrain() {
+	atomic.StoreInt32(&service.draining, 1)
+	log.Printf("[Azure CNS] Draining: no longer accepting new create requests.")
+}
+
+// IsDraining reports whether the service has started draining.
+func (service *HTTPRestService) IsDraining() bool {
+	return atomic.LoadInt32(&service.draining) == 1
+}
+
+// trackRequest increments the in-flight request count and returns a func
+// that decrements it again; callers defer the returned func.
+func (service *HTTPRestService) trackRequest() func() {
+	atomic.AddInt32(&service.inFlight, 1)
+	return func() {
+		atomic.AddInt32(&service.inFlight, -1)
+	}
+}
+
+// InFlightCount returns the number of CreateEndpoint calls currently in
+// progress; other routes do not call trackRequest and are not counted.
+func (service *HTTPRestService) InFlightCount() int {
+	return int(atomic.LoadInt32(&service.inFlight))
+}
+
+// WaitForDrain blocks until the in-flight request count reaches zero or
+// timeout elapses, whichever comes first. It reports whether the drain
+// completed before the timeout.
+func (service *HTTPRestService) WaitForDrain(timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if service.InFlightCount() == 0 {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+			continue
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// healthzHandler reports liveness: CNS responds as long as its process is
+// up, regardless of drain state.
+func (service *HTTPRestService) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// readyzHandler reports readiness: CNS stops advertising ready as soon as
+// it starts draining, so an orchestrator's rollout stops sending it new
+// work while it finishes in-flight requests.
+func (service *HTTPRestService) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if service.IsDraining() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,36 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitForDrainReturnsTrueOnceInFlightReachesZero(t *testing.T) {
+	service := newTestService(t)
+
+	release := service.trackRequest()
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- service.WaitForDrain(time.Second)
+	}()
+
+	release()
+
+	if !<-done {
+		t.Fatal("expected WaitForDrain to return true once the in-flight request completed")
+	}
+}
+
+func TestWaitForDrainTimesOutWithRequestsStillInFlight(t *testing.T) {
+	service := newTestService(t)
+
+	defer service.trackRequest()()
+
+	if service.WaitForDrain(50 * time.Millisecond) {
+		t.Fatal("expected WaitForDrain to time out while a request is still in flight")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// createSingleEndpoint provisions one network attachment and records it in
+// the service's endpoint state. It is the unit of work CreateEndpoint
+// invokes once per entry in a multi-network request. Reserving the address
+// happens before the endpoint is recorded, and outside service.mutex, so a
+// failed reservation leaves no endpoint state behind for CreateEndpoint's
+// rollback to have to undo.
+func (service *HTTPRestService) createSingleEndpoint(containerID string, attachment NetworkAttachment) (string, []string, string, error) {
+	reservation, err := service.ReserveAddress(containerID, attachment.NetworkName)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	endpointID := containerID + "-" + attachment.NetworkName
+
+	ipAddresses := attachment.IPAddresses
+	if len(ipAddresses) == 0 {
+		ipAddresses = []string{reservation.IPAddress}
+	}
+
+	state := &endpointState{
+		containerID: containerID,
+		networkName: attachment.NetworkName,
+		ifName:      attachment.IfName,
+		ipAddresses: ipAddresses,
+		gateway:     reservation.Gateway,
+	}
+
+	service.endpoints[endpointID] = state
+
+	log.Printf("[Azure CNS] Created endpoint %v for container %v on network %v.",
+		endpointID, containerID, attachment.NetworkName)
+
+	return endpointID, state.ipAddresses, state.gateway, nil
+}
+
+// deleteSingleEndpoint tears down one previously provisioned attachment,
+// releasing its IPAM reservation along with the endpoint state. It is the
+// unit of work CreateEndpoint's rollback invokes per already-succeeded
+// attachment when a later attachment in the same request fails.
+func (service *HTTPRestService) deleteSingleEndpoint(containerID, networkName, endpointID string) error {
+	service.mutex.Lock()
+	if _, ok := service.endpoints[endpointID]; !ok {
+		service.mutex.Unlock()
+		return fmt.Errorf("deleteSingleEndpoint: endpoint %v not found", endpointID)
+	}
+
+	delete(service.endpoints, endpointID)
+	service.mutex.Unlock()
+
+	if err := service.ReleaseAddress(containerID, networkName); err != nil {
+		log.Printf("[Azure CNS] Failed to release address for endpoint %v, err:%v.", endpointID, err)
+	}
+
+	log.Printf("[Azure CNS] Deleted endpoint %v for container %v on network %v.",
+		endpointID, containerID, networkName)
+
+	return nil
+}
+
+// DeleteEndpoint tears down the endpoint CNS holds for a container on a
+// single network. Used by the CNI DEL verb, once per network it names.
+func (service *HTTPRestService) DeleteEndpoint(containerID, networkName string) error {
+	return service.deleteSingleEndpoint(containerID, networkName, containerID+"-"+networkName)
+}
+
+// CheckEndpoint validates that CNS still has state for a container's
+// endpoint on a network, for the CNI CHECK verb.
+func (service *HTTPRestService) CheckEndpoint(containerID, networkName string) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	endpointID := containerID + "-" + networkName
+	if _, ok := service.endpoints[endpointID]; !ok {
+		return fmt.Errorf("CheckEndpoint: endpoint %v not found", endpointID)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// These routes are what the standalone azure-vnet and azure-vnet-ipam CNI
+// binaries call into: each CNI invocation is a fresh, short-lived process
+// with no access to the long-running CNS daemon's in-memory state, so it
+// reaches that state over this HTTP surface instead of in-process.
+const (
+	networkCreateRoute = "/network/create"
+	networkDeleteRoute = "/network/delete"
+	networkCheckRoute  = "/network/check"
+	addressReserveRoute = "/ipam/reserve"
+	addressReleaseRoute = "/ipam/release"
+	addressCheckRoute   = "/ipam/check"
+)
+
+// endpointRequest is the payload shared by the delete and check routes,
+// which only need to name the container/network pair, not a full
+// CreateEndpointRequest.
+type endpointRequest struct {
+	ContainerID string `json:"containerID"`
+	NetworkName string `json:"networkName"`
+}
+
+func (service *HTTPRestService) registerHandlers(mux *http.ServeMux) {
+	mux.HandleFunc(networkCreateRoute, service.handleCreateEndpoint)
+	mux.HandleFunc(networkDeleteRoute, service.handleDeleteEndpoint)
+	mux.HandleFunc(networkCheckRoute, service.handleCheckEndpoint)
+	mux.HandleFunc(addressReserveRoute, service.handleReserveAddress)
+	mux.HandleFunc(addressReleaseRoute, service.handleReleaseAddress)
+	mux.HandleFunc(addressCheckRoute, service.handleCheckAddress)
+}
+
+func (service *HTTPRestService) handleCreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req CreateEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := service.CreateEndpoint(&req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, resp)
+}
+
+func (service *HTTPRestService) handleDeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req endpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := service.DeleteEndpoint(req.ContainerID, req.NetworkName); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (service *HTTPRestService) handleCheckEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req endpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := service.CheckEndpoint(req.ContainerID, req.NetworkName); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (service *HTTPRestService) handleReserveAddress(w http.ResponseWriter, r *http.Request) {
+	var req endpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := service.ReserveAddress(req.ContainerID, req.NetworkName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, resp)
+}
+
+func (service *HTTPRestService) handleReleaseAddress(w http.ResponseWriter, r *http.Request) {
+	var req endpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := service.ReleaseAddress(req.ContainerID, req.NetworkName); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (service *HTTPRestService) handleCheckAddress(w http.ResponseWriter, r *http.Request) {
+	var req endpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := service.CheckAddress(req.ContainerID, req.NetworkName); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if err == ErrDraining {
+		status = http.StatusServiceUnavailable
+	}
+
+	http.Error(w, err.Error(), status)
+}
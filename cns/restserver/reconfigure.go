@@ -0,0 +1,175 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/Azure/azure-container-networking/cns/configuration"
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// Reconfigure applies a newly loaded config file to the running service
+// without restarting it, so in-memory endpoint state in service.endpoints
+// survives the update.
+func (service *HTTPRestService) Reconfigure(cfg *configuration.CNSConfig) {
+	service.mutex.Lock()
+	service.apiServerURL = cfg.APIServerURL
+	addressChanged := cfg.ListenAddress != "" && cfg.ListenAddress != service.listenAddress
+	if addressChanged {
+		service.listenAddress = cfg.ListenAddress
+	}
+	service.mutex.Unlock()
+
+	if level, ok := logLevelFromString(cfg.LogLevel); ok {
+		log.SetLevel(level)
+	}
+
+	if target, ok := logTargetFromString(cfg.LogTarget); ok {
+		if err := log.SetTarget(target); err != nil {
+			log.Printf("[Azure CNS] Failed to apply reconfigured log target %v, err:%v.", cfg.LogTarget, err)
+		}
+	}
+
+	// TLS reload already rebinds the listener to service.listenAddress, so
+	// it takes care of a changed ListenAddress too; only reload the plain
+	// listener here if TLS isn't in play.
+	switch {
+	case cfg.TLS.CertFile != "" && cfg.TLS.KeyFile != "":
+		if err := service.reloadTLS(cfg.TLS); err != nil {
+			log.Printf("[Azure CNS] Failed to reload TLS listener, err:%v.", err)
+		}
+	case addressChanged:
+		if err := service.reloadAddr(); err != nil {
+			log.Printf("[Azure CNS] Failed to reload REST service listener, err:%v.", err)
+		}
+	}
+
+	log.Printf("[Azure CNS] Reconfigured REST service from config file.")
+}
+
+// logLevelFromString maps the config file's logLevel string to the log
+// package's level constants. It reports false for an empty or unrecognized
+// value so Reconfigure leaves the current level alone rather than resetting
+// it to a default.
+func logLevelFromString(level string) (int, bool) {
+	switch level {
+	case "debug":
+		return log.LevelDebug, true
+	case "info":
+		return log.LevelInfo, true
+	default:
+		return 0, false
+	}
+}
+
+// logTargetFromString maps the config file's logTarget string to the log
+// package's target constants, the same way logLevelFromString does for
+// level.
+func logTargetFromString(target string) (int, bool) {
+	switch target {
+	case "syslog":
+		return log.TargetSyslog, true
+	case "stderr":
+		return log.TargetStderr, true
+	case "file":
+		return log.TargetLogfile, true
+	default:
+		return 0, false
+	}
+}
+
+// reloadTLS swaps the running listener for one serving TLS with the given
+// certificate material. The old listener is closed only after the new one
+// is ready to accept connections, so a momentary failure to load the new
+// certificate doesn't leave the REST API unreachable.
+func (service *HTTPRestService) reloadTLS(cfg configuration.TLSConfig) error {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	service.mutex.Lock()
+	addr := service.listenAddress
+	old := service.httpServer
+	service.mutex.Unlock()
+
+	newServer := &http.Server{
+		Addr:      addr,
+		Handler:   service.serverMux(),
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+
+	listenAddr := addr
+	if listenAddr == "" {
+		listenAddr = ":https"
+	}
+
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := newServer.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			log.Printf("[Azure CNS] REST service TLS listener stopped with err:%v.", err)
+		}
+	}()
+
+	service.mutex.Lock()
+	service.httpServer = newServer
+	service.mutex.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	log.Printf("[Azure CNS] Reloaded REST service listener with TLS certificate %v.", cfg.CertFile)
+	return nil
+}
+
+// reloadAddr swaps the running listener for a plain HTTP listener bound to
+// the current service.listenAddress, using the same start-new-before-
+// closing-old sequence as reloadTLS, so changing listenAddress in the
+// config file doesn't leave the REST API briefly unreachable.
+func (service *HTTPRestService) reloadAddr() error {
+	service.mutex.Lock()
+	addr := service.listenAddress
+	old := service.httpServer
+	service.mutex.Unlock()
+
+	newServer := &http.Server{
+		Addr:    addr,
+		Handler: service.serverMux(),
+	}
+
+	listenAddr := addr
+	if listenAddr == "" {
+		listenAddr = ":http"
+	}
+
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := newServer.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("[Azure CNS] REST service listener stopped with err:%v.", err)
+		}
+	}()
+
+	service.mutex.Lock()
+	service.httpServer = newServer
+	service.mutex.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	log.Printf("[Azure CNS] Reloaded REST service listener on %v.", addr)
+	return nil
+}
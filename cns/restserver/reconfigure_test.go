@@ -0,0 +1,49 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package restserver
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-container-networking/log"
+)
+
+func TestLogLevelFromString(t *testing.T) {
+	cases := map[string]struct {
+		want int
+		ok   bool
+	}{
+		"debug":   {log.LevelDebug, true},
+		"info":    {log.LevelInfo, true},
+		"":        {0, false},
+		"bogus":   {0, false},
+	}
+
+	for input, expected := range cases {
+		got, ok := logLevelFromString(input)
+		if ok != expected.ok || (ok && got != expected.want) {
+			t.Errorf("logLevelFromString(%q) = (%v, %v), want (%v, %v)", input, got, ok, expected.want, expected.ok)
+		}
+	}
+}
+
+func TestLogTargetFromString(t *testing.T) {
+	cases := map[string]struct {
+		want int
+		ok   bool
+	}{
+		"syslog": {log.TargetSyslog, true},
+		"stderr": {log.TargetStderr, true},
+		"file":   {log.TargetLogfile, true},
+		"":       {0, false},
+		"bogus":  {0, false},
+	}
+
+	for input, expected := range cases {
+		got, ok := logTargetFromString(input)
+		if ok != expected.ok || (ok && got != expected.want) {
+			t.Errorf("logTargetFromString(%q) = (%v, %v), want (%v, %v)", input, got, ok, expected.want, expected.ok)
+		}
+	}
+}
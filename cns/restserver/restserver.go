@@ -0,0 +1,118 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package restserver implements the CNS REST API: it owns the endpoint and
+// IPAM state CNS tracks on behalf of the CNM and CNI plugins that front it.
+package restserver
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/Azure/azure-container-networking/cns/common"
+	acn "github.com/Azure/azure-container-networking/common"
+	"github.com/Azure/azure-container-networking/log"
+)
+
+// serverMux is the set of routes the REST API serves, rebuilt by reloadTLS
+// when the listener has to be recreated to pick up new certificate
+// material.
+func (service *HTTPRestService) serverMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", service.healthzHandler)
+	mux.HandleFunc("/readyz", service.readyzHandler)
+	service.registerHandlers(mux)
+	return mux
+}
+
+// endpointState is the in-memory record CNS keeps for one provisioned
+// network attachment, keyed by endpoint ID.
+type endpointState struct {
+	containerID string
+	networkName string
+	ifName      string
+	ipAddresses []string
+	gateway     string
+}
+
+// HTTPRestService implements the CNS REST API.
+type HTTPRestService struct {
+	config       *common.ServiceConfig
+	apiServerURL string
+
+	mutex     sync.Mutex
+	endpoints map[string]*endpointState
+
+	addresses    map[string]*addressState
+	addressIndex int
+
+	// draining and inFlight back BeginDrain/IsDraining/trackRequest; they
+	// are accessed without the mutex so health checks never block behind
+	// endpoint mutations.
+	draining   int32
+	inFlight   int32
+	httpServer *http.Server
+
+	// listenAddress is remembered from Start so reloadTLS can recreate the
+	// listener on the same address once new certificate material arrives.
+	listenAddress string
+}
+
+// NewHTTPRestService creates a new HTTPRestService object.
+func NewHTTPRestService(config *common.ServiceConfig) (*HTTPRestService, error) {
+	return &HTTPRestService{
+		config:    config,
+		endpoints: make(map[string]*endpointState),
+		addresses: make(map[string]*addressState),
+	}, nil
+}
+
+// SetOption sets an option on the service.
+func (service *HTTPRestService) SetOption(key, value interface{}) {
+	if key == acn.OptAPIServerURL {
+		if url, ok := value.(string); ok {
+			service.apiServerURL = url
+		}
+	}
+}
+
+// Start starts the CNS REST API, including the /healthz and /readyz
+// endpoints orchestrators use to coordinate rollouts against drain state.
+func (service *HTTPRestService) Start(config *common.ServiceConfig) error {
+	log.Printf("[Azure CNS] Starting REST service.")
+
+	service.listenAddress = config.ListenAddress
+	service.httpServer = &http.Server{
+		Addr:    config.ListenAddress,
+		Handler: service.serverMux(),
+	}
+
+	go func() {
+		if err := service.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[Azure CNS] REST service listener stopped with err:%v.", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop force-stops the CNS REST API immediately. Callers that want an
+// orderly shutdown should call BeginDrain and WaitForDrain first.
+func (service *HTTPRestService) Stop() {
+	if service.httpServer != nil {
+		service.httpServer.Close()
+	}
+
+	log.Printf("[Azure CNS] Stopped REST service.")
+}
+
+// Shutdown closes the REST API listener (which also serves /healthz and
+// /readyz) gracefully, allowing any connection already accepted to finish.
+func (service *HTTPRestService) Shutdown(ctx context.Context) error {
+	if service.httpServer == nil {
+		return nil
+	}
+
+	return service.httpServer.Shutdown(ctx)
+}
@@ -7,11 +7,16 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/Azure/azure-container-networking/cnm/ipam"
-	"github.com/Azure/azure-container-networking/cnm/network"
+	cniipam "github.com/Azure/azure-container-networking/cni/ipam"
+	cninetwork "github.com/Azure/azure-container-networking/cni/network"
+	cnmipam "github.com/Azure/azure-container-networking/cnm/ipam"
+	cnmnetwork "github.com/Azure/azure-container-networking/cnm/network"
 	"github.com/Azure/azure-container-networking/cns/common"
+	"github.com/Azure/azure-container-networking/cns/configuration"
 	"github.com/Azure/azure-container-networking/cns/restserver"
 	acn "github.com/Azure/azure-container-networking/common"
 	"github.com/Azure/azure-container-networking/log"
@@ -23,6 +28,15 @@ const (
 	// Service name.
 	name       = "azure-cns"
 	pluginName = "azure-vnet"
+
+	// Plugin API modes.
+	pluginAPICNM  = "cnm"
+	pluginAPICNI  = "cni"
+	pluginAPIBoth = "both"
+
+	// defaultShutdownGraceSeconds bounds how long CNS waits for in-flight
+	// create requests to drain before forcing a shutdown.
+	defaultShutdownGraceSeconds = 30
 )
 
 // Version is populated by make during build.
@@ -86,6 +100,51 @@ var args = acn.ArgumentList{
 		Type:         "bool",
 		DefaultValue: false,
 	},
+	{
+		Name:         acn.OptPluginAPI,
+		Shorthand:    acn.OptPluginAPIAlias,
+		Description:  "Set the plugin API to expose: cnm, cni or both",
+		Type:         "string",
+		DefaultValue: pluginAPICNM,
+		ValueMap: map[string]interface{}{
+			pluginAPICNM:  0,
+			pluginAPICNI:  0,
+			pluginAPIBoth: 0,
+		},
+	},
+	{
+		Name:         acn.OptConfigFile,
+		Shorthand:    acn.OptConfigFileAlias,
+		Description:  "Set the path to a declarative config file; flags above become defaults it can override",
+		Type:         "string",
+		DefaultValue: "",
+	},
+	{
+		Name:         acn.OptShutdownGrace,
+		Shorthand:    acn.OptShutdownGraceAlias,
+		Description:  "Set how long to wait for in-flight requests to drain on SIGTERM, in seconds",
+		Type:         "int",
+		DefaultValue: defaultShutdownGraceSeconds,
+	},
+	{
+		Name:         acn.OptStoreBackend,
+		Shorthand:    acn.OptStoreBackendAlias,
+		Description:  "Set the persistent store backend: json, boltdb or etcd",
+		Type:         "string",
+		DefaultValue: string(store.BackendJSON),
+		ValueMap: map[string]interface{}{
+			string(store.BackendJSON):   0,
+			string(store.BackendBoltDB): 0,
+			string(store.BackendEtcd):   0,
+		},
+	},
+	{
+		Name:         acn.OptStoreEndpoints,
+		Shorthand:    acn.OptStoreEndpointsAlias,
+		Description:  "Set the comma-separated etcd endpoints, used only when --store=etcd",
+		Type:         "string",
+		DefaultValue: "",
+	},
 }
 
 // Prints description and version information.
@@ -105,6 +164,11 @@ func main() {
 	logTarget := acn.GetArg(acn.OptLogTarget).(int)
 	ipamQueryInterval, _ := acn.GetArg(acn.OptIpamQueryInterval).(int)
 	vers := acn.GetArg(acn.OptVersion).(bool)
+	pluginAPI := acn.GetArg(acn.OptPluginAPI).(string)
+	configFile := acn.GetArg(acn.OptConfigFile).(string)
+	shutdownGraceSeconds, _ := acn.GetArg(acn.OptShutdownGrace).(int)
+	storeBackend := store.Backend(acn.GetArg(acn.OptStoreBackend).(string))
+	storeEndpoints := acn.GetArg(acn.OptStoreEndpoints).(string)
 
 	if vers {
 		printVersion()
@@ -121,7 +185,7 @@ func main() {
 
 	// Create the key value store.
 	var err error
-	config.Store, err = store.NewJsonFileStore(platform.CNMRuntimePath + name + ".json")
+	config.Store, err = createStore(storeBackend, storeEndpoints, platform.CNMRuntimePath+name+".json", name)
 	if err != nil {
 		fmt.Printf("Failed to create store: %v\n", err)
 		return
@@ -140,22 +204,70 @@ func main() {
 	// Create a channel to receive unhandled errors from the plugins.
 	pluginConfig.ErrChan = make(chan error, 1)
 
-	// Create network plugin.
-	netPlugin, err := network.NewPlugin(&pluginConfig)
-	if err != nil {
-		fmt.Printf("Failed to create network plugin, err:%v.\n", err)
-		return
+	enableCNM := pluginAPI == pluginAPICNM || pluginAPI == pluginAPIBoth
+	enableCNI := pluginAPI == pluginAPICNI || pluginAPI == pluginAPIBoth
+
+	// A declarative config file's plugins section, if it enables anything,
+	// overrides the --plugin-api flag the same way the rest of the config
+	// file overrides its corresponding flag default. Only the plugin
+	// surfaces live at startup can be hot-reloaded later; toggling which
+	// surfaces are hosted is not, since that would mean constructing and
+	// tearing down plugins at runtime rather than just updating their
+	// settings. listenAddress, by contrast, applies to the REST service
+	// that's about to be started below and can also be hot-reloaded later
+	// via Reconfigure.
+	if configFile != "" {
+		if cfg, loadErr := configuration.Load(configFile); loadErr == nil {
+			if cfg.Plugins.CNM || cfg.Plugins.CNI {
+				enableCNM = cfg.Plugins.CNM
+				enableCNI = cfg.Plugins.CNI
+			}
+
+			if cfg.ListenAddress != "" {
+				config.ListenAddress = cfg.ListenAddress
+			}
+		}
 	}
 
-	// Create IPAM plugin.
-	ipamPlugin, err := ipam.NewPlugin(&pluginConfig)
-	if err != nil {
-		fmt.Printf("Failed to create IPAM plugin, err:%v.\n", err)
-		return
+	// Create CNM network and IPAM plugins, if enabled.
+	var netPlugin *cnmnetwork.Plugin
+	var ipamPlugin *cnmipam.Plugin
+	if enableCNM {
+		netPlugin, err = cnmnetwork.NewPlugin(&pluginConfig)
+		if err != nil {
+			fmt.Printf("Failed to create network plugin, err:%v.\n", err)
+			return
+		}
+
+		ipamPlugin, err = cnmipam.NewPlugin(&pluginConfig)
+		if err != nil {
+			fmt.Printf("Failed to create IPAM plugin, err:%v.\n", err)
+			return
+		}
+	}
+
+	// Create CNI network and IPAM plugins, if enabled.
+	var cniNetPlugin *cninetwork.Plugin
+	var cniIpamPlugin *cniipam.Plugin
+	if enableCNI {
+		cniNetPlugin, err = cninetwork.NewPlugin(&pluginConfig)
+		if err != nil {
+			fmt.Printf("Failed to create CNI network plugin, err:%v.\n", err)
+			return
+		}
+
+		cniIpamPlugin, err = cniipam.NewPlugin(&pluginConfig)
+		if err != nil {
+			fmt.Printf("Failed to create CNI IPAM plugin, err:%v.\n", err)
+			return
+		}
+
+		cniNetPlugin.SetOption(acn.OptRestService, httpRestService)
+		cniIpamPlugin.SetOption(acn.OptRestService, httpRestService)
 	}
 
 	// Create the key value store.
-	pluginConfig.Store, err = store.NewJsonFileStore(platform.CNMRuntimePath + pluginName + ".json")
+	pluginConfig.Store, err = createStore(storeBackend, storeEndpoints, platform.CNMRuntimePath+pluginName+".json", pluginName)
 	if err != nil {
 		fmt.Printf("Failed to create store: %v\n", err)
 		return
@@ -186,11 +298,15 @@ func main() {
 	}
 
 	// Set plugin options.
-	netPlugin.SetOption(acn.OptAPIServerURL, url)
+	if netPlugin != nil {
+		netPlugin.SetOption(acn.OptAPIServerURL, url)
+	}
 
-	ipamPlugin.SetOption(acn.OptEnvironment, environment)
-	ipamPlugin.SetOption(acn.OptAPIServerURL, url)
-	ipamPlugin.SetOption(acn.OptIpamQueryInterval, ipamQueryInterval)
+	if ipamPlugin != nil {
+		ipamPlugin.SetOption(acn.OptEnvironment, environment)
+		ipamPlugin.SetOption(acn.OptAPIServerURL, url)
+		ipamPlugin.SetOption(acn.OptIpamQueryInterval, ipamQueryInterval)
+	}
 
 	if netPlugin != nil {
 		log.Printf("Start netplugin\n")
@@ -209,28 +325,159 @@ func main() {
 		}
 	}
 
-	// Relay these incoming signals to OS signal channel.
+	if cniNetPlugin != nil {
+		log.Printf("Start CNI netplugin\n")
+		err = cniNetPlugin.Start(&pluginConfig)
+		if err != nil {
+			fmt.Printf("Failed to start CNI network plugin, err:%v.\n", err)
+			return
+		}
+	}
+
+	if cniIpamPlugin != nil {
+		err = cniIpamPlugin.Start(&pluginConfig)
+		if err != nil {
+			fmt.Printf("Failed to start CNI IPAM plugin, err:%v.\n", err)
+			return
+		}
+	}
+
+	// Watch the declarative config file, if one was given, and push updates
+	// to every live plugin so the daemon can be reconfigured without losing
+	// in-memory endpoint state.
+	if configFile != "" {
+		err = configuration.Watch(configFile, func(cfg *configuration.CNSConfig) {
+			httpRestService.Reconfigure(cfg)
+
+			if netPlugin != nil {
+				netPlugin.Reconfigure(cfg)
+			}
+
+			if ipamPlugin != nil {
+				ipamPlugin.Reconfigure(cfg)
+			}
+
+			if cniNetPlugin != nil {
+				cniNetPlugin.Reconfigure(cfg)
+			}
+
+			if cniIpamPlugin != nil {
+				cniIpamPlugin.Reconfigure(cfg)
+			}
+		})
+		if err != nil {
+			fmt.Printf("Failed to watch config file %v, err:%v.\n", configFile, err)
+			return
+		}
+	}
+
+	// Relay these incoming signals to OS signal channel. SIGTERM is handled
+	// on its own below so a second one can force an immediate shutdown;
+	// os.Interrupt and os.Kill stop CNS right away as before.
 	osSignalChannel := make(chan os.Signal, 1)
-	signal.Notify(osSignalChannel, os.Interrupt, os.Kill, syscall.SIGTERM)
+	termSignalChannel := make(chan os.Signal, 2)
+	signal.Notify(osSignalChannel, os.Interrupt, os.Kill)
+	signal.Notify(termSignalChannel, syscall.SIGTERM)
 
 	// Wait until receiving a signal.
 	select {
 	case sig := <-osSignalChannel:
 		log.Printf("CNS Received OS signal <" + sig.String() + ">, shutting down.")
+	case <-termSignalChannel:
+		log.Printf("CNS received SIGTERM, draining before shutdown.")
+		drainAndStop(httpRestService, termSignalChannel, time.Duration(shutdownGraceSeconds)*time.Second)
 	case err := <-config.ErrChan:
 		log.Printf("CNS Received unhandled error %v, shutting down.", err)
 	}
 
-	// Cleanup.
-	if httpRestService != nil {
-		httpRestService.Stop()
-	}
-
+	// Cleanup plugins in dependency order: network before IPAM, CNM/CNI
+	// plugins before the REST service whose state they depend on.
 	if netPlugin != nil {
 		netPlugin.Stop()
 	}
 
+	if cniNetPlugin != nil {
+		cniNetPlugin.Stop()
+	}
+
 	if ipamPlugin != nil {
 		ipamPlugin.Stop()
 	}
+
+	if cniIpamPlugin != nil {
+		cniIpamPlugin.Stop()
+	}
+
+	if httpRestService != nil {
+		httpRestService.Stop()
+	}
+}
+
+// createStore builds the configured persistent store backend for bucket
+// (the CNS or CNM plugin state file), migrating the legacy JSON file at
+// legacyJSONPath into it the first time a non-JSON backend is selected.
+func createStore(backend store.Backend, endpointsCSV, legacyJSONPath, bucket string) (store.KeyValueStore, error) {
+	switch backend {
+	case store.BackendJSON, "":
+		return store.NewStore(store.BackendJSON, store.Options{Path: legacyJSONPath})
+
+	case store.BackendBoltDB:
+		boltPath := strings.TrimSuffix(legacyJSONPath, ".json") + ".db"
+
+		kvStore, err := store.NewStore(store.BackendBoltDB, store.Options{Path: boltPath, Bucket: bucket})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := store.MigrateJSONFile(legacyJSONPath, kvStore); err != nil {
+			return nil, err
+		}
+
+		return kvStore, nil
+
+	case store.BackendEtcd:
+		var endpoints []string
+		if endpointsCSV != "" {
+			endpoints = strings.Split(endpointsCSV, ",")
+		}
+
+		kvStore, err := store.NewStore(store.BackendEtcd, store.Options{Endpoints: endpoints, Bucket: bucket})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := store.MigrateJSONFile(legacyJSONPath, kvStore); err != nil {
+			return nil, err
+		}
+
+		return kvStore, nil
+
+	default:
+		return nil, &store.UnsupportedBackendError{Backend: backend}
+	}
+}
+
+// drainAndStop marks httpRestService as draining, returning once either
+// in-flight requests reach zero, grace elapses, or a second SIGTERM
+// arrives on termSignalChannel demanding an immediate stop.
+func drainAndStop(httpRestService *restserver.HTTPRestService, termSignalChannel <-chan os.Signal, grace time.Duration) {
+	if httpRestService == nil {
+		return
+	}
+
+	httpRestService.BeginDrain()
+
+	drained := make(chan bool, 1)
+	go func() {
+		drained <- httpRestService.WaitForDrain(grace)
+	}()
+
+	select {
+	case ok := <-drained:
+		if !ok {
+			log.Printf("CNS shutdown grace period elapsed with %v requests still in flight.", httpRestService.InFlightCount())
+		}
+	case <-termSignalChannel:
+		log.Printf("CNS received second SIGTERM, forcing shutdown.")
+	}
 }
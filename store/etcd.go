@@ -0,0 +1,116 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	concurrency "go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const etcdRequestTimeout = 5 * time.Second
+
+// etcdStore is a KeyValueStore backed by an etcd v3 cluster, letting
+// several CNS replicas share endpoint state for HA deployments.
+type etcdStore struct {
+	client *clientv3.Client
+	prefix string
+
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+}
+
+// NewEtcdStore creates an etcd-backed KeyValueStore talking to the given
+// endpoints, namespacing all keys under prefix.
+func NewEtcdStore(endpoints []string, prefix string) (KeyValueStore, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: etcdRequestTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := concurrency.NewSession(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &etcdStore{
+		client:  client,
+		prefix:  prefix,
+		session: session,
+		mutex:   concurrency.NewMutex(session, "/"+prefix+"/lock"),
+	}, nil
+}
+
+func (s *etcdStore) key(key string) string {
+	return "/" + s.prefix + "/" + key
+}
+
+func (s *etcdStore) Read(key string, value interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := s.client.Get(ctx, s.key(key))
+	if err != nil {
+		return err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return os.ErrNotExist
+	}
+
+	return json.Unmarshal(resp.Kvs[0].Value, value)
+}
+
+func (s *etcdStore) Write(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	_, err = s.client.Put(ctx, s.key(key), string(raw))
+	return err
+}
+
+// Flush is a no-op: every Write is committed to the etcd cluster before it
+// returns.
+func (s *etcdStore) Flush() error {
+	return nil
+}
+
+// Lock acquires a cluster-wide lock so only one CNS replica mutates
+// endpoint state at a time. Lock(false) uses TryLock so it returns
+// immediately with os.ErrExist when another replica already holds the
+// lock, matching boltDBStore and jsonFileStore's non-blocking semantics
+// instead of waiting out etcdRequestTimeout.
+func (s *etcdStore) Lock(block bool) error {
+	if !block {
+		if err := s.mutex.TryLock(context.Background()); err != nil {
+			if errors.Is(err, concurrency.ErrLocked) {
+				return os.ErrExist
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	return s.mutex.Lock(context.Background())
+}
+
+func (s *etcdStore) Unlock(forceUnlock bool) error {
+	return s.mutex.Unlock(context.Background())
+}
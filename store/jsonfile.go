@@ -0,0 +1,112 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// jsonFileStore is a KeyValueStore backed by a single JSON file. Every
+// Write rewrites the whole file, so it does not scale well to nodes that
+// churn endpoints quickly; see BackendBoltDB for an alternative.
+type jsonFileStore struct {
+	path string
+
+	mutex sync.Mutex
+	data  map[string]json.RawMessage
+
+	// lockMutex backs the advisory Lock/Unlock pair callers use to hold
+	// the store across a read-modify-write sequence spanning multiple
+	// keys; it is distinct from mutex, which only protects data itself.
+	lockMutex sync.Mutex
+}
+
+// NewJsonFileStore creates a JSON file-backed KeyValueStore at path.
+func NewJsonFileStore(path string) (KeyValueStore, error) {
+	store := &jsonFileStore{
+		path: path,
+		data: make(map[string]json.RawMessage),
+	}
+
+	if err := store.load(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *jsonFileStore) load() error {
+	bytes, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(bytes, &s.data)
+}
+
+func (s *jsonFileStore) Read(key string, value interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	raw, ok := s.data[key]
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	return json.Unmarshal(raw, value)
+}
+
+func (s *jsonFileStore) Write(key string, value interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.data[key] = raw
+
+	return s.flushLocked()
+}
+
+func (s *jsonFileStore) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.flushLocked()
+}
+
+func (s *jsonFileStore) flushLocked() error {
+	bytes, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(s.path, bytes, 0o644)
+}
+
+func (s *jsonFileStore) Lock(block bool) error {
+	if !block {
+		if !s.lockMutex.TryLock() {
+			return os.ErrExist
+		}
+		return nil
+	}
+
+	s.lockMutex.Lock()
+	return nil
+}
+
+func (s *jsonFileStore) Unlock(forceUnlock bool) error {
+	s.lockMutex.Unlock()
+	return nil
+}
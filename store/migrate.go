@@ -0,0 +1,54 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// MigrateJSONFile reads the legacy single-file JSON store at jsonPath and
+// writes every record it contains into dest. It is meant to run once, the
+// first time CNS starts against a non-JSON backend, so an operator can
+// switch backends without losing previously provisioned endpoint state.
+// A missing jsonPath is not an error: a fresh node has nothing to migrate,
+// and a node that already migrated has nothing left to do either, since a
+// successful migration renames jsonPath out of the way so it is never
+// replayed over state the new backend has since moved on from.
+func MigrateJSONFile(jsonPath string, dest KeyValueStore) error {
+	bytes, err := ioutil.ReadFile(jsonPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if len(bytes) == 0 {
+		return nil
+	}
+
+	var records map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &records); err != nil {
+		return err
+	}
+
+	for key, raw := range records {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+
+		if err := dest.Write(key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := dest.Flush(); err != nil {
+		return err
+	}
+
+	return os.Rename(jsonPath, jsonPath+".migrated")
+}
@@ -0,0 +1,113 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeStore is a minimal in-memory KeyValueStore, standing in for a real
+// backend so MigrateJSONFile's behavior can be tested without BoltDB or
+// etcd.
+type fakeStore struct {
+	values map[string]interface{}
+	locked bool
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{values: make(map[string]interface{})}
+}
+
+func (s *fakeStore) Read(key string, value interface{}) error {
+	v, ok := s.values[key]
+	if !ok {
+		return os.ErrNotExist
+	}
+
+	p, ok := value.(*interface{})
+	if !ok {
+		return nil
+	}
+
+	*p = v
+	return nil
+}
+
+func (s *fakeStore) Write(key string, value interface{}) error {
+	s.values[key] = value
+	return nil
+}
+
+func (s *fakeStore) Flush() error { return nil }
+
+func (s *fakeStore) Lock(block bool) error {
+	if s.locked && !block {
+		return os.ErrExist
+	}
+
+	s.locked = true
+	return nil
+}
+
+func (s *fakeStore) Unlock(forceUnlock bool) error {
+	s.locked = false
+	return nil
+}
+
+func TestMigrateJSONFileMissingSourceIsNotAnError(t *testing.T) {
+	dest := newFakeStore()
+
+	if err := MigrateJSONFile(filepath.Join(t.TempDir(), "missing.json"), dest); err != nil {
+		t.Fatalf("expected a missing source file to be a no-op, got err: %v", err)
+	}
+
+	if len(dest.values) != 0 {
+		t.Fatalf("expected nothing written to dest, got %v", dest.values)
+	}
+}
+
+func TestMigrateJSONFileCopiesRecordsAndRenamesSource(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"container1-net1":{"ip":"10.0.0.4/24"}}`), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	dest := newFakeStore()
+
+	if err := MigrateJSONFile(jsonPath, dest); err != nil {
+		t.Fatalf("MigrateJSONFile failed: %v", err)
+	}
+
+	if _, ok := dest.values["container1-net1"]; !ok {
+		t.Fatalf("expected the migrated record to be written to dest, got %v", dest.values)
+	}
+
+	if _, err := os.Stat(jsonPath); !os.IsNotExist(err) {
+		t.Fatal("expected the source file to be renamed out of the way after a successful migration")
+	}
+
+	if _, err := os.Stat(jsonPath + ".migrated"); err != nil {
+		t.Fatalf("expected a .migrated file to exist, err: %v", err)
+	}
+}
+
+func TestMigrateJSONFileIsANoOpOnceAlreadyMigrated(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"container1-net1":{"ip":"10.0.0.4/24"}}`), 0o600); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	dest := newFakeStore()
+	if err := MigrateJSONFile(jsonPath, dest); err != nil {
+		t.Fatalf("first migration failed: %v", err)
+	}
+
+	// A second call, as createStore makes on every CNS restart, must not
+	// find the renamed-away source file and must not re-run the migration.
+	if err := MigrateJSONFile(jsonPath, dest); err != nil {
+		t.Fatalf("second migration over an already-migrated file should be a no-op, got err: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 Microsoft. All rights reserved.
+// MIT License
+
+// Package store persists CNS and CNM plugin state: endpoint records, IPAM
+// reservations, and anything else a restart needs to recover. Backend
+// names which implementation NewStore constructs; KeyValueStore is the
+// interface every implementation satisfies and what callers code against
+// so the backend can be swapped without touching them.
+package store
+
+// KeyValueStore reads and writes a single named record to durable storage.
+// CNS and the CNM plugins each keep one KeyValueStore for their own state
+// file; they do not share one across processes unless the backend itself
+// supports concurrent access (etcd does, the JSON file and BoltDB do not).
+type KeyValueStore interface {
+	// Read loads the named key into value, which must be a pointer.
+	Read(key string, value interface{}) error
+	// Write persists value under the named key.
+	Write(key string, value interface{}) error
+	// Flush forces any buffered writes to durable storage.
+	Flush() error
+	// Lock acquires an exclusive lock on the store for the duration of a
+	// read-modify-write sequence across multiple keys.
+	Lock(block bool) error
+	// Unlock releases a lock acquired by Lock.
+	Unlock(forceUnlock bool) error
+}
+
+// Backend identifies which KeyValueStore implementation to construct.
+type Backend string
+
+const (
+	// BackendJSON is the original single-file JSON store. It rewrites the
+	// whole file on every mutation, which becomes a bottleneck on nodes
+	// that churn endpoints quickly.
+	BackendJSON Backend = "json"
+	// BackendBoltDB is an embedded, transactional, single-file store with
+	// O(log n) writes. It is the recommended default for a single CNS
+	// instance that wants crash consistency without an external dependency.
+	BackendBoltDB Backend = "boltdb"
+	// BackendEtcd is an etcd v3-backed store for HA deployments where
+	// several CNS replicas need to share endpoint state.
+	BackendEtcd Backend = "etcd"
+)
+
+// Options configures NewStore. Only the fields relevant to the selected
+// Backend need to be set.
+type Options struct {
+	// Path is the file path for BackendJSON and BackendBoltDB.
+	Path string
+	// Endpoints is the list of etcd server addresses for BackendEtcd.
+	Endpoints []string
+	// Bucket is the BoltDB bucket (and etcd key prefix) records are stored
+	// under. Defaults to "cns" if empty.
+	Bucket string
+}
+
+// NewStore constructs the KeyValueStore for the given backend and options.
+func NewStore(backend Backend, options Options) (KeyValueStore, error) {
+	if options.Bucket == "" {
+		options.Bucket = "cns"
+	}
+
+	switch backend {
+	case BackendJSON, "":
+		return NewJsonFileStore(options.Path)
+	case BackendBoltDB:
+		return NewBoltDBStore(options.Path, options.Bucket)
+	case BackendEtcd:
+		return NewEtcdStore(options.Endpoints, options.Bucket)
+	default:
+		return nil, &UnsupportedBackendError{Backend: backend}
+	}
+}
+
+// UnsupportedBackendError is returned by NewStore for an unrecognized
+// Backend value.
+type UnsupportedBackendError struct {
+	Backend Backend
+}
+
+func (e *UnsupportedBackendError) Error() string {
+	return "store: unsupported backend " + string(e.Backend)
+}